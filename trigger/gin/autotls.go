@@ -0,0 +1,184 @@
+package gin
+
+import (
+	"crypto/tls"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/project-flogo/core/support/log"
+)
+
+// defaultCipherSuites is used for TLS endpoints that don't already specify
+// their own, favoring AEAD suites with forward secrecy.
+var defaultCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+}
+
+// AutoTLS option provisions and renews certificates automatically via
+// ACME/Let's Encrypt for endpoints that request TLS (see Endpoint.TLS),
+// instead of a static cert/key pair. hosts restricts which server names the
+// manager will request certificates for; certificates are cached under
+// cacheDir. The ACME HTTP-01 challenge handler is served on :80.
+func AutoTLS(hosts []string, cacheDir string) Option {
+	return func(s *Server) {
+		s.autoTLSEnabled = true
+		s.autocertManager = &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(hosts...),
+			Cache:      autocert.DirCache(cacheDir),
+		}
+	}
+}
+
+// TLSReload option watches the cert/key pair configured via TLS with
+// fsnotify and swaps the serving certificate when they change on disk,
+// without requiring the server to be restarted.
+func TLSReload() Option {
+	return func(s *Server) {
+		s.tlsReloadEnabled = true
+	}
+}
+
+// certificateSource returns the GetCertificate callback TLS endpoints
+// should use, based on which of AutoTLS/TLSReload/TLS was configured.
+func (s *Server) certificateSource() (func(*tls.ClientHelloInfo) (*tls.Certificate, error), error) {
+
+	if s.autoTLSEnabled {
+		return s.autocertManager.GetCertificate, nil
+	}
+
+	if s.certFile == "" || s.keyFile == "" {
+		return nil, fmt.Errorf("when TLS is enabled, both cert file and key file must be specified")
+	}
+
+	if s.tlsReloadEnabled {
+		reloader, err := newCertReloader(s.certFile, s.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		s.certReloader = reloader
+		return reloader.GetCertificate, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return &cert, nil
+	}, nil
+}
+
+// certReloader serves a TLS certificate that's reloaded from disk whenever
+// its source files change.
+type certReloader struct {
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	watcher *fsnotify.Watcher
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &certReloader{cert: &cert}, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+func (r *certReloader) reload(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+
+	return nil
+}
+
+// watch starts a background fsnotify watcher that reloads the certificate
+// whenever certFile or keyFile changes.
+func (r *certReloader) watch(certFile, keyFile string) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	// Watch the containing directories rather than the files themselves,
+	// since editors and `cp` typically replace files via rename rather
+	// than writing to them in place.
+	dirs := map[string]bool{filepath.Dir(certFile): true, filepath.Dir(keyFile): true}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+
+	certFile, keyFile = filepath.Clean(certFile), filepath.Clean(keyFile)
+
+	r.watcher = watcher
+
+	go func() {
+		defer watcher.Close()
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				name := filepath.Clean(event.Name)
+				if name != certFile && name != keyFile {
+					continue
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+
+				if err := r.reload(certFile, keyFile); err != nil {
+					log.RootLogger().Errorf("Error reloading TLS certificate: %s", err.Error())
+					continue
+				}
+				log.RootLogger().Info("Reloaded TLS certificate")
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.RootLogger().Error(err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// close stops the background watcher started by watch, if any, so its
+// goroutine can exit. The Server calls this from Stop so a reload/restart
+// within the same process doesn't leak a watcher per Start.
+func (r *certReloader) close() error {
+	if r.watcher == nil {
+		return nil
+	}
+	return r.watcher.Close()
+}
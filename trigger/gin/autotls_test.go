@@ -0,0 +1,92 @@
+package gin
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestCertReloaderReload checks that reload swaps in a certificate loaded
+// from a different cert/key pair.
+func TestCertReloaderReload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	generateSelfSignedCert(t, certFile, keyFile)
+
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("newCertReloader: %v", err)
+	}
+
+	original, _ := reloader.GetCertificate(nil)
+
+	generateSelfSignedCert(t, certFile, keyFile)
+
+	if err := reloader.reload(certFile, keyFile); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+
+	reloaded, _ := reloader.GetCertificate(nil)
+	if reloaded.Leaf != nil && original.Leaf != nil && reloaded.Leaf.SerialNumber.Cmp(original.Leaf.SerialNumber) == 0 {
+		t.Fatalf("expected reload to swap in a different certificate")
+	}
+	if string(reloaded.Certificate[0]) == string(original.Certificate[0]) {
+		t.Fatalf("expected reload to swap in a different certificate")
+	}
+}
+
+// TestCertReloaderWatchPicksUpChange checks that watch reloads the
+// certificate when its files change on disk.
+func TestCertReloaderWatchPicksUpChange(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	generateSelfSignedCert(t, certFile, keyFile)
+
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("newCertReloader: %v", err)
+	}
+	if err := reloader.watch(certFile, keyFile); err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	defer reloader.close()
+
+	original, _ := reloader.GetCertificate(nil)
+
+	generateSelfSignedCert(t, certFile, keyFile)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		current, _ := reloader.GetCertificate(nil)
+		if string(current.Certificate[0]) != string(original.Certificate[0]) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("certificate was never reloaded after the files changed")
+}
+
+// TestCertReloaderClose checks that close stops the watcher without
+// error, and is a safe no-op when watch was never called.
+func TestCertReloaderClose(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	generateSelfSignedCert(t, certFile, keyFile)
+
+	reloader, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("newCertReloader: %v", err)
+	}
+
+	if err := reloader.close(); err != nil {
+		t.Fatalf("close on an unwatched reloader: %v", err)
+	}
+
+	if err := reloader.watch(certFile, keyFile); err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	if err := reloader.close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+}
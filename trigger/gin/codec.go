@@ -0,0 +1,359 @@
+package gin
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// media types understood by the built-in codecs.
+const (
+	mimeJSON      = "application/json"
+	mimeForm      = "application/x-www-form-urlencoded"
+	mimeMultipart = "multipart/form-data"
+	mimeXML       = "application/xml"
+	mimeYAML      = "application/yaml"
+	mimeMsgpack   = "application/msgpack"
+	mimeProtobuf  = "application/protobuf"
+)
+
+// Codec decodes a request body into a value suitable for an Output's
+// Content, and encodes a handler's reply data back onto the response
+// writer. Codecs are looked up by media type via a CodecRegistry.
+type Codec interface {
+	Decode(r *http.Request) (interface{}, error)
+	Encode(w http.ResponseWriter, v interface{}) error
+}
+
+// CodecRegistry maps a media type to the Codec that handles it.
+type CodecRegistry map[string]Codec
+
+// Codecs option overrides or extends the server's request/response codec
+// registry, keyed by media type. Built-in codecs are registered by
+// default; passing the same media type replaces the built-in.
+func Codecs(codecs map[string]Codec) Option {
+	return func(s *Server) {
+		if s.codecs == nil {
+			s.codecs = defaultCodecs()
+		}
+		for mediaType, codec := range codecs {
+			s.codecs[mediaType] = codec
+		}
+	}
+}
+
+// nonEncodableMediaTypes are decode-only: form and multipart bodies have no
+// meaningful response encoding, so negotiateCodec must never select their
+// built-in codecs (whose Encode always errors) for a reply.
+var nonEncodableMediaTypes = map[string]bool{
+	mimeForm:      true,
+	mimeMultipart: true,
+}
+
+// negotiateCodec picks a response codec from the Accept header, preferring
+// the first acceptable, encodable media type the trigger has a codec for
+// and falling back to JSON.
+func (t *Trigger) negotiateCodec(accept string) (Codec, string) {
+	for _, mediaType := range acceptedMediaTypes(accept) {
+		if nonEncodableMediaTypes[mediaType] {
+			continue
+		}
+		if codec, ok := t.server.codecs[mediaType]; ok {
+			return codec, mediaType
+		}
+	}
+	return t.server.codecs[mimeJSON], mimeJSON
+}
+
+// acceptedMediaTypes returns the media types listed in an Accept header, in
+// the order they appeared (quality values are not considered).
+func acceptedMediaTypes(accept string) []string {
+	if accept == "" {
+		return nil
+	}
+
+	parts := strings.Split(accept, ",")
+	types := make([]string, 0, len(parts))
+	for _, p := range parts {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(p))
+		if err != nil || mediaType == "*/*" {
+			continue
+		}
+		types = append(types, mediaType)
+	}
+	return types
+}
+
+// defaultCodecs returns the codec registry used when the Codecs option is
+// not supplied.
+func defaultCodecs() CodecRegistry {
+	return CodecRegistry{
+		mimeJSON:      jsonCodec{},
+		mimeForm:      formCodec{},
+		mimeMultipart: multipartCodec{},
+		mimeXML:       xmlCodec{},
+		mimeYAML:      yamlCodec{},
+		mimeMsgpack:   msgpackCodec{},
+		mimeProtobuf:  protobufCodec{},
+	}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Decode(r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	var content interface{}
+	if err := json.NewDecoder(r.Body).Decode(&content); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (jsonCodec) Encode(w http.ResponseWriter, v interface{}) error {
+	w.Header().Set("Content-Type", mimeJSON+"; charset=UTF-8")
+	return json.NewEncoder(w).Encode(v)
+}
+
+type formCodec struct{}
+
+func (formCodec) Decode(r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	buf := new(bytes.Buffer)
+	if _, err := buf.ReadFrom(r.Body); err != nil {
+		return nil, err
+	}
+
+	values, err := url.ParseQuery(buf.String())
+	if err != nil {
+		return nil, err
+	}
+
+	content := make(map[string]interface{}, len(values))
+	for key, val := range values {
+		content[key] = val[0]
+	}
+
+	return content, nil
+}
+
+func (formCodec) Encode(w http.ResponseWriter, v interface{}) error {
+	return fmt.Errorf("form codec does not support encoding responses")
+}
+
+// defaultMultipartMaxMemory mirrors net/http's own default for
+// Request.ParseMultipartForm.
+const defaultMultipartMaxMemory = 32 << 20
+
+type multipartCodec struct{}
+
+// Decode parses the multipart form and returns a map of field name to
+// value, with file parts exposed as open multipart.File streams so large
+// uploads aren't buffered into memory twice.
+func (multipartCodec) Decode(r *http.Request) (interface{}, error) {
+	if err := r.ParseMultipartForm(defaultMultipartMaxMemory); err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	content := make(map[string]interface{}, len(r.MultipartForm.Value)+len(r.MultipartForm.File))
+
+	for key, values := range r.MultipartForm.Value {
+		if len(values) == 1 {
+			content[key] = values[0]
+		} else {
+			content[key] = values
+		}
+	}
+
+	for key, headers := range r.MultipartForm.File {
+		files := make([]multipart.File, 0, len(headers))
+		for _, h := range headers {
+			f, err := h.Open()
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, f)
+		}
+
+		if len(files) == 1 {
+			content[key] = files[0]
+		} else {
+			content[key] = files
+		}
+	}
+
+	return content, nil
+}
+
+func (multipartCodec) Encode(w http.ResponseWriter, v interface{}) error {
+	return fmt.Errorf("multipart codec does not support encoding responses")
+}
+
+// closeMultipartContent closes the multipart.File streams Decode opened for
+// content and removes r's parsed multipart form, including any part
+// ParseMultipartForm spilled to a temp file on disk. The caller owns this
+// cleanup and must call it once it's done reading content, since the files
+// are handed over open so large uploads aren't buffered into memory twice.
+func closeMultipartContent(r *http.Request, content interface{}) {
+	if values, ok := content.(map[string]interface{}); ok {
+		for _, v := range values {
+			switch f := v.(type) {
+			case multipart.File:
+				f.Close()
+			case []multipart.File:
+				for _, ff := range f {
+					ff.Close()
+				}
+			}
+		}
+	}
+
+	if r.MultipartForm != nil {
+		r.MultipartForm.RemoveAll()
+	}
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) Decode(r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+	return decodeXMLElement(xml.NewDecoder(r.Body))
+}
+
+func (xmlCodec) Encode(w http.ResponseWriter, v interface{}) error {
+	w.Header().Set("Content-Type", mimeXML+"; charset=UTF-8")
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// decodeXMLElement reads the document's root element into a generic
+// map[string]interface{}, since the target Go type isn't known up front. An
+// element holding only text (no attributes or children) decodes to that
+// text as a plain string.
+func decodeXMLElement(d *xml.Decoder) (interface{}, error) {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return decodeXMLChildren(d, start)
+		}
+	}
+}
+
+func decodeXMLChildren(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	content := make(map[string]interface{})
+	for _, attr := range start.Attr {
+		content["@"+attr.Name.Local] = attr.Value
+	}
+
+	var text strings.Builder
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLChildren(d, t)
+			if err != nil {
+				return nil, err
+			}
+
+			if existing, ok := content[t.Name.Local]; ok {
+				if list, ok := existing.([]interface{}); ok {
+					content[t.Name.Local] = append(list, child)
+				} else {
+					content[t.Name.Local] = []interface{}{existing, child}
+				}
+			} else {
+				content[t.Name.Local] = child
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if text.Len() > 0 {
+				if len(content) == 0 {
+					return strings.TrimSpace(text.String()), nil
+				}
+				content["#text"] = strings.TrimSpace(text.String())
+			}
+			return content, nil
+		}
+	}
+}
+
+type yamlCodec struct{}
+
+func (yamlCodec) Decode(r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	var content interface{}
+	if err := yaml.NewDecoder(r.Body).Decode(&content); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (yamlCodec) Encode(w http.ResponseWriter, v interface{}) error {
+	w.Header().Set("Content-Type", mimeYAML+"; charset=UTF-8")
+	return yaml.NewEncoder(w).Encode(v)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Decode(r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+
+	var content interface{}
+	if err := msgpack.NewDecoder(r.Body).Decode(&content); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return content, nil
+}
+
+func (msgpackCodec) Encode(w http.ResponseWriter, v interface{}) error {
+	w.Header().Set("Content-Type", mimeMsgpack)
+	return msgpack.NewEncoder(w).Encode(v)
+}
+
+type protobufCodec struct{}
+
+// Decode returns the raw protobuf-encoded bytes; the concrete message type
+// is only known to the downstream activity holding the .proto schema, so
+// unmarshalling happens there.
+func (protobufCodec) Decode(r *http.Request) (interface{}, error) {
+	defer r.Body.Close()
+	return io.ReadAll(r.Body)
+}
+
+func (protobufCodec) Encode(w http.ResponseWriter, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("protobuf codec requires a proto.Message reply, got %T", v)
+	}
+
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", mimeProtobuf)
+	_, err = w.Write(b)
+	return err
+}
@@ -0,0 +1,155 @@
+package gin
+
+import (
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeCodec is a minimal Codec used to prove a registry override actually
+// takes effect, as opposed to the built-in codecs which would mask the
+// bug this test guards against.
+type fakeCodec struct{}
+
+func (fakeCodec) Decode(r *http.Request) (interface{}, error) { return "fake", nil }
+
+func (fakeCodec) Encode(w http.ResponseWriter, v interface{}) error {
+	_, err := w.Write([]byte("fake"))
+	return err
+}
+
+// TestTriggerCodecOverrideReachesNegotiation guards against the Codecs
+// option being dead code: overriding mimeJSON before the server is built
+// must be visible to negotiateCodec, which reads the trigger's server, not
+// a separate unreconciled copy of the registry.
+func TestTriggerCodecOverrideReachesNegotiation(t *testing.T) {
+	trig := &Trigger{codecs: defaultCodecs()}
+	trig.codecs[mimeJSON] = fakeCodec{}
+
+	srv, err := NewServer(nil, http.NotFoundHandler(), Codecs(trig.codecs))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	trig.server = srv
+
+	if _, ok := trig.server.codecs[mimeJSON].(fakeCodec); !ok {
+		t.Fatalf("server codecs were not seeded with the override")
+	}
+
+	codec, mediaType := trig.negotiateCodec("application/json")
+	if mediaType != mimeJSON {
+		t.Fatalf("expected negotiated media type %q, got %q", mimeJSON, mediaType)
+	}
+	if _, ok := codec.(fakeCodec); !ok {
+		t.Fatalf("expected trigger to negotiate the overridden codec, got %T", codec)
+	}
+}
+
+// TestNegotiateCodecSkipsDecodeOnlyMediaTypes ensures form/multipart are
+// never selected to encode a response, since their Encode always errors.
+func TestNegotiateCodecSkipsDecodeOnlyMediaTypes(t *testing.T) {
+	srv, err := NewServer(nil, http.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	trig := &Trigger{server: srv}
+
+	codec, mediaType := trig.negotiateCodec("multipart/form-data, application/json")
+	if mediaType != mimeJSON {
+		t.Fatalf("expected negotiation to fall through to %q, got %q", mimeJSON, mediaType)
+	}
+	if _, ok := codec.(jsonCodec); !ok {
+		t.Fatalf("expected jsonCodec, got %T", codec)
+	}
+}
+
+// TestMultipartCodecDecode exercises a full multipart request through
+// multipartCodec.Decode and checks the decoded field/file shape.
+func TestMultipartCodecDecode(t *testing.T) {
+	body := &strings.Builder{}
+	writer := multipart.NewWriter(body)
+	if err := writer.WriteField("name", "flogo"); err != nil {
+		t.Fatalf("WriteField: %v", err)
+	}
+	part, err := writer.CreateFormFile("upload", "data.bin")
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	if _, err := part.Write([]byte("payload")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body.String()))
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	content, err := multipartCodec{}.Decode(req)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	values, ok := content.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected map[string]interface{}, got %T", content)
+	}
+	if values["name"] != "flogo" {
+		t.Fatalf("expected field name=flogo, got %v", values["name"])
+	}
+
+	f, ok := values["upload"].(multipart.File)
+	if !ok {
+		t.Fatalf("expected upload field to be a multipart.File, got %T", values["upload"])
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(data) != "payload" {
+		t.Fatalf("expected payload %q, got %q", "payload", data)
+	}
+
+	closeMultipartContent(req, content)
+}
+
+// fakeMultipartFile is a multipart.File whose Close is observable, used to
+// test closeMultipartContent's cleanup logic directly rather than relying
+// on whether a given upload was small enough to be held in memory.
+type fakeMultipartFile struct {
+	closed bool
+}
+
+func (f *fakeMultipartFile) Read(p []byte) (int, error)                   { return 0, io.EOF }
+func (f *fakeMultipartFile) ReadAt(p []byte, off int64) (int, error)      { return 0, io.EOF }
+func (f *fakeMultipartFile) Seek(offset int64, whence int) (int64, error) { return 0, nil }
+func (f *fakeMultipartFile) Close() error                                 { f.closed = true; return nil }
+
+// TestCloseMultipartContentClosesFiles ensures every multipart.File Decode
+// handed out, single or repeated per field, gets closed.
+func TestCloseMultipartContentClosesFiles(t *testing.T) {
+	single := &fakeMultipartFile{}
+	multi := []multipart.File{&fakeMultipartFile{}, &fakeMultipartFile{}}
+
+	content := map[string]interface{}{
+		"single": multipart.File(single),
+		"multi":  multi,
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	closeMultipartContent(req, content)
+
+	if !single.closed {
+		t.Fatalf("expected single file to be closed")
+	}
+	for i, f := range multi {
+		if !f.(*fakeMultipartFile).closed {
+			t.Fatalf("expected file %d to be closed", i)
+		}
+	}
+}
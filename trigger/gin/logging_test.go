@@ -0,0 +1,68 @@
+package gin
+
+import (
+	"encoding/hex"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/project-flogo/core/support/log"
+)
+
+func TestGenerateRequestIDIsUniqueAndHex(t *testing.T) {
+	a := generateRequestID()
+	b := generateRequestID()
+
+	if a == "" || b == "" {
+		t.Fatalf("expected non-empty request IDs")
+	}
+	if a == b {
+		t.Fatalf("expected two calls to generate different request IDs")
+	}
+	if len(a) != 32 {
+		t.Fatalf("expected a 32-char hex request ID, got %q", a)
+	}
+	if _, err := hex.DecodeString(a); err != nil {
+		t.Fatalf("expected a hex request ID, got %q", a)
+	}
+}
+
+// TestLoggingMiddlewarePreservesIncomingRequestID checks that a caller-
+// supplied X-Request-Id is echoed back rather than overwritten.
+func TestLoggingMiddlewarePreservesIncomingRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	router := gin.New()
+
+	router.Use(newLoggingMiddleware(log.RootLogger(), "text"))
+	router.GET("/", func(c *gin.Context) { c.Status(200) })
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(requestIDHeader, "caller-supplied-id")
+
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get(requestIDHeader); got != "caller-supplied-id" {
+		t.Fatalf("expected request ID %q to be echoed back, got %q", "caller-supplied-id", got)
+	}
+}
+
+// TestLoggingMiddlewareGeneratesRequestID checks that a request without an
+// X-Request-Id gets one assigned.
+func TestLoggingMiddlewareGeneratesRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	router := gin.New()
+
+	router.Use(newLoggingMiddleware(log.RootLogger(), "text"))
+	router.GET("/", func(c *gin.Context) { c.Status(200) })
+
+	req := httptest.NewRequest("GET", "/", nil)
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get(requestIDHeader); got == "" {
+		t.Fatalf("expected a generated request ID header")
+	}
+}
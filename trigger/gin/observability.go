@@ -0,0 +1,117 @@
+package gin
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// requestMetrics holds the Prometheus collectors recorded per request by
+// newMetricsMiddleware.
+type requestMetrics struct {
+	duration       *prometheus.HistogramVec
+	requestSize    *prometheus.HistogramVec
+	responseSize   *prometheus.HistogramVec
+	activeRequests *prometheus.GaugeVec
+}
+
+// newRequestMetrics creates the trigger's request metrics and registers
+// them with reg. If an equivalent collector is already registered (e.g. by
+// another gin-trigger instance sharing reg, or a prior Initialize of this
+// same instance), the existing collector is reused instead of panicking.
+func newRequestMetrics(reg prometheus.Registerer) *requestMetrics {
+	return &requestMetrics{
+		duration: registerHistogramVec(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "http_server_duration_seconds",
+			Help: "Duration of HTTP requests handled by the REST trigger.",
+		}, []string{"method", "path", "status"})),
+		requestSize: registerHistogramVec(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "http_server_request_size_bytes",
+			Help: "Size of HTTP request bodies handled by the REST trigger.",
+		}, []string{"method", "path"})),
+		responseSize: registerHistogramVec(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "http_server_response_size_bytes",
+			Help: "Size of HTTP response bodies written by the REST trigger.",
+		}, []string{"method", "path"})),
+		activeRequests: registerGaugeVec(reg, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "http_server_active_requests",
+			Help: "Number of in-flight HTTP requests handled by the REST trigger.",
+		}, []string{"method", "path"})),
+	}
+}
+
+// registerHistogramVec registers hv with reg, returning the already
+// registered collector if an equivalent one was registered previously.
+func registerHistogramVec(reg prometheus.Registerer, hv *prometheus.HistogramVec) *prometheus.HistogramVec {
+	if err := reg.Register(hv); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector.(*prometheus.HistogramVec)
+		}
+		panic(err)
+	}
+	return hv
+}
+
+// registerGaugeVec registers gv with reg, returning the already registered
+// collector if an equivalent one was registered previously.
+func registerGaugeVec(reg prometheus.Registerer, gv *prometheus.GaugeVec) *prometheus.GaugeVec {
+	if err := reg.Register(gv); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector.(*prometheus.GaugeVec)
+		}
+		panic(err)
+	}
+	return gv
+}
+
+// newMetricsMiddleware records http.server.duration, http.server.request.size,
+// http.server.response.size and http.server.active_requests for every
+// request.
+func newMetricsMiddleware(m *requestMetrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.FullPath()
+		if path == "" {
+			path = c.Request.URL.Path
+		}
+		method := c.Request.Method
+
+		m.activeRequests.WithLabelValues(method, path).Inc()
+		defer m.activeRequests.WithLabelValues(method, path).Dec()
+
+		start := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		m.duration.WithLabelValues(method, path, statusLabel(status)).Observe(time.Since(start).Seconds())
+		if reqSize := c.Request.ContentLength; reqSize >= 0 {
+			m.requestSize.WithLabelValues(method, path).Observe(float64(reqSize))
+		}
+		m.responseSize.WithLabelValues(method, path).Observe(float64(c.Writer.Size()))
+	}
+}
+
+func statusLabel(status int) string {
+	switch {
+	case status >= 500:
+		return "5xx"
+	case status >= 400:
+		return "4xx"
+	case status >= 300:
+		return "3xx"
+	case status >= 200:
+		return "2xx"
+	default:
+		return "unknown"
+	}
+}
+
+// newTracingMiddleware wraps otelgin.Middleware, creating a server span per
+// request (extracting traceparent from incoming headers) using tp as the
+// tracer provider.
+func newTracingMiddleware(serviceName string, tp oteltrace.TracerProvider) gin.HandlerFunc {
+	return otelgin.Middleware(serviceName, otelgin.WithTracerProvider(tp))
+}
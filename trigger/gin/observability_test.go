@@ -0,0 +1,66 @@
+package gin
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TestRegisterHistogramVecReusesExisting ensures a second registration of
+// an equivalent HistogramVec reuses the one already registered instead of
+// panicking with an AlreadyRegisteredError.
+func TestRegisterHistogramVecReusesExisting(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	first := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "test_histogram",
+		Help: "test",
+	}, []string{"a"})
+	got := registerHistogramVec(reg, first)
+	if got != first {
+		t.Fatalf("expected the first registration to return itself")
+	}
+
+	second := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "test_histogram",
+		Help: "test",
+	}, []string{"a"})
+	got = registerHistogramVec(reg, second)
+	if got != first {
+		t.Fatalf("expected the existing collector to be reused, not %p", got)
+	}
+}
+
+// TestRegisterGaugeVecReusesExisting mirrors
+// TestRegisterHistogramVecReusesExisting for GaugeVec.
+func TestRegisterGaugeVecReusesExisting(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	first := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "test_gauge",
+		Help: "test",
+	}, []string{"a"})
+	got := registerGaugeVec(reg, first)
+	if got != first {
+		t.Fatalf("expected the first registration to return itself")
+	}
+
+	second := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "test_gauge",
+		Help: "test",
+	}, []string{"a"})
+	got = registerGaugeVec(reg, second)
+	if got != first {
+		t.Fatalf("expected the existing collector to be reused, not %p", got)
+	}
+}
+
+// TestNewRequestMetricsDoesNotPanicOnReuse simulates a second gin-trigger
+// instance (or a re-Initialize of the same one) sharing a registry: it
+// must not panic, which is what MustRegister used to do here.
+func TestNewRequestMetricsDoesNotPanicOnReuse(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	newRequestMetrics(reg)
+	newRequestMetrics(reg)
+}
@@ -7,72 +7,228 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"os"
 	"time"
 
+	"golang.org/x/crypto/acme/autocert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
 	"github.com/project-flogo/core/support/log"
 )
 
 const (
-	httpDefaultAddr    = ":8080"
-	httpDefaultTlsAddr = ":8443"
+	httpDefaultAddr        = ":8080"
+	httpDefaultTlsAddr     = ":8443"
+	httpDefaultMetricsAddr = ":9090"
 
 	httpDefaultReadTimeout  = 60 * time.Second
 	httpDefaultWriteTimeout = 60 * time.Second
 )
 
+// Endpoint describes one address a Server listens on. Network defaults to
+// "tcp" when empty; "tcp4", "tcp6" and "unix" are also accepted. A non-nil
+// TLS marks the endpoint as HTTPS - its GetCertificate, NextProtos,
+// MinVersion and CipherSuites are filled in from the server's TLS/AutoTLS
+// option if not already populated, so an
+// empty &tls.Config{} is enough to opt an endpoint into the shared cert.
+type Endpoint struct {
+	Network string
+	Addr    string
+	TLS     *tls.Config
+}
+
 type Option func(*Server)
 
-// TLS option enables TLS on the server
+// TLS option enables TLS on endpoints that request it (see Endpoint.TLS),
+// loading the certificate/key pair to use for them.
 func TLS(certFile, keyFile string) Option {
 	return func(s *Server) {
 		s.tlsEnabled = true
 		s.certFile = certFile
 		s.keyFile = keyFile
-
-		if s.srv.Addr == "" {
-			s.srv.Addr = httpDefaultTlsAddr
-		}
 	}
 }
 
 // Timeouts options lets you set the read and write timeouts of the server
 func Timeouts(readTimeout, writeTimeout time.Duration) Option {
 	return func(s *Server) {
-		s.srv.ReadTimeout = readTimeout
-		s.srv.WriteTimeout = writeTimeout
+		s.readTimeout = readTimeout
+		s.writeTimeout = writeTimeout
+	}
+}
+
+// HTTP2 option enables HTTP/2 on the server. When the server is also
+// configured with TLS, the connection negotiates h2 via ALPN; pass nil to
+// use http2's default settings.
+func HTTP2(h2s *http2.Server) Option {
+	return func(s *Server) {
+		if h2s == nil {
+			h2s = &http2.Server{}
+		}
+		s.http2Enabled = true
+		s.http2Srv = h2s
+	}
+}
+
+// H2C option enables cleartext HTTP/2 (h2c) by wrapping the handler with an
+// h2c.NewHandler, allowing HTTP/2 to be used without TLS.
+func H2C() Option {
+	return func(s *Server) {
+		s.h2cEnabled = true
+	}
+}
+
+// Observability option installs a Prometheus /metrics endpoint, served on
+// its own listener at metricsAddr (defaulting to httpDefaultMetricsAddr),
+// using reg's registered collectors. tp is stored on the Server but not
+// used by it: this option installs no tracing middleware, and request spans
+// come only from newTracingMiddleware installed on the router.
+func Observability(tp oteltrace.TracerProvider, reg prometheus.Registerer, metricsAddr string) Option {
+	return func(s *Server) {
+		s.tracerProvider = tp
+		s.metricsRegisterer = reg
+		s.metricsAddr = metricsAddr
+	}
+}
+
+// SocketActivation option configures the server to obtain its listeners
+// from file descriptors passed by systemd/init following the LISTEN_FDS/
+// LISTEN_PID convention, instead of binding new sockets in Start. index
+// selects the first of the passed descriptors to use; one endpoint's
+// listener is taken from each subsequent descriptor, in the order the
+// endpoints were given to NewServer.
+func SocketActivation(index int) Option {
+	return func(s *Server) {
+		s.socketActivation = true
+		s.socketIndex = index
 	}
 }
 
 type Server struct {
 	running bool
-	srv     *http.Server
+
+	endpoints []Endpoint
+	servers   []*http.Server
+	listeners []net.Listener
+	handler   http.Handler
+
+	readTimeout  time.Duration
+	writeTimeout time.Duration
 
 	tlsEnabled bool
 	certFile   string
 	keyFile    string
+
+	http2Enabled bool
+	http2Srv     *http2.Server
+	h2cEnabled   bool
+
+	socketActivation bool
+	socketIndex      int
+
+	codecs CodecRegistry
+
+	tracerProvider    oteltrace.TracerProvider
+	metricsRegisterer prometheus.Registerer
+	metricsAddr       string
+	metricsSrv        *http.Server
+
+	autoTLSEnabled  bool
+	autocertManager *autocert.Manager
+	acmeSrv         *http.Server
+
+	tlsReloadEnabled bool
+	certReloader     *certReloader
 }
 
-func NewServer(addr string, handler http.Handler, opts ...Option) (*Server, error) {
-	if addr == "" {
-		addr = httpDefaultAddr
+// NewServer builds a Server that listens on each of endpoints, sharing
+// handler and all other settings across them. A nil/empty endpoints serves
+// a single default endpoint (httpDefaultAddr, or httpDefaultTlsAddr if the
+// TLS option is used) for backwards-compatible single-address use.
+func NewServer(endpoints []Endpoint, handler http.Handler, opts ...Option) (*Server, error) {
+	usingDefaultEndpoint := len(endpoints) == 0
+	if usingDefaultEndpoint {
+		endpoints = []Endpoint{{Addr: httpDefaultAddr}}
 	}
 
-	srv := &Server{}
-	srv.srv = &http.Server{
-		Addr:         addr,
-		Handler:      handler,
-		ReadTimeout:  httpDefaultReadTimeout,
-		WriteTimeout: httpDefaultWriteTimeout,
+	srv := &Server{
+		endpoints:    endpoints,
+		handler:      handler,
+		codecs:       defaultCodecs(),
+		readTimeout:  httpDefaultReadTimeout,
+		writeTimeout: httpDefaultWriteTimeout,
 	}
 
 	for _, opt := range opts {
 		opt(srv)
 	}
 
+	if usingDefaultEndpoint && srv.tlsEnabled {
+		srv.endpoints[0].Addr = httpDefaultTlsAddr
+		srv.endpoints[0].TLS = &tls.Config{}
+	}
+
 	if err := srv.validateInit(); err != nil {
 		return nil, err
 	}
 
+	finalHandler := srv.handler
+	if srv.h2cEnabled {
+		h2s := srv.http2Srv
+		if h2s == nil {
+			h2s = &http2.Server{}
+		}
+		finalHandler = h2c.NewHandler(finalHandler, h2s)
+	}
+
+	srv.servers = make([]*http.Server, len(srv.endpoints))
+	for i, ep := range srv.endpoints {
+		httpSrv := &http.Server{
+			Addr:         ep.Addr,
+			Handler:      finalHandler,
+			ReadTimeout:  srv.readTimeout,
+			WriteTimeout: srv.writeTimeout,
+			TLSConfig:    ep.TLS,
+		}
+
+		if srv.http2Enabled && ep.TLS != nil {
+			if err := http2.ConfigureServer(httpSrv, srv.http2Srv); err != nil {
+				return nil, err
+			}
+		}
+
+		srv.servers[i] = httpSrv
+	}
+
+	if srv.metricsRegisterer != nil {
+		metricsAddr := srv.metricsAddr
+		if metricsAddr == "" {
+			metricsAddr = httpDefaultMetricsAddr
+		}
+
+		gatherer, ok := srv.metricsRegisterer.(prometheus.Gatherer)
+		if !ok {
+			gatherer = prometheus.DefaultGatherer
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+		srv.metricsSrv = &http.Server{Addr: metricsAddr, Handler: mux}
+	}
+
+	if srv.autoTLSEnabled {
+		srv.acmeSrv = &http.Server{
+			Addr:    ":80",
+			Handler: srv.autocertManager.HTTPHandler(nil),
+		}
+	}
+
 	return srv, nil
 }
 
@@ -86,33 +242,38 @@ func (s *Server) Start() error {
 		return err
 	}
 
-	fullAddr := s.srv.Addr
-	if fullAddr[0] == ':' {
-		fullAddr = "0.0.0.0" + s.srv.Addr
+	s.listeners = make([]net.Listener, len(s.servers))
+	if s.socketActivation {
+		for i := range s.servers {
+			ln, err := activationListener(s.socketIndex + i)
+			if err != nil {
+				return err
+			}
+			s.listeners[i] = ln
+		}
 	}
 
 	s.running = true
 
-	if s.tlsEnabled {
-
+	if s.metricsSrv != nil {
 		go func() {
 
-			log.RootLogger().Infof("Rest Trigger listening on https://%s", fullAddr)
+			log.RootLogger().Infof("Rest Trigger metrics listening on http://%s/metrics", s.metricsSrv.Addr)
 
-			if err := s.srv.ListenAndServeTLS(s.certFile, s.keyFile); err != nil {
-				s.running = false
+			if err := s.metricsSrv.ListenAndServe(); err != nil {
 				if !errors.Is(err, http.ErrServerClosed) {
 					log.RootLogger().Error(err)
 				}
 			}
 		}()
-	} else {
+	}
+
+	if s.acmeSrv != nil {
 		go func() {
 
-			log.RootLogger().Infof("Rest Trigger listening on http://%s", fullAddr)
+			log.RootLogger().Infof("Rest Trigger ACME HTTP-01 challenge listening on http://%s", s.acmeSrv.Addr)
 
-			if err := s.srv.ListenAndServe(); err != nil {
-				s.running = false
+			if err := s.acmeSrv.ListenAndServe(); err != nil {
 				if !errors.Is(err, http.ErrServerClosed) {
 					log.RootLogger().Error(err)
 				}
@@ -120,6 +281,60 @@ func (s *Server) Start() error {
 		}()
 	}
 
+	if s.certReloader != nil {
+		if err := s.certReloader.watch(s.certFile, s.keyFile); err != nil {
+			return err
+		}
+	}
+
+	var g errgroup.Group
+
+	for i := range s.servers {
+		i := i
+		httpSrv := s.servers[i]
+		ep := s.endpoints[i]
+
+		g.Go(func() error {
+			ln := s.listeners[i]
+			if ln == nil {
+				network := ep.Network
+				if network == "" {
+					network = "tcp"
+				}
+
+				var err error
+				ln, err = net.Listen(network, httpSrv.Addr)
+				if err != nil {
+					return err
+				}
+			}
+
+			scheme := "http"
+			if ep.TLS != nil {
+				scheme = "https"
+			}
+			log.RootLogger().Infof("Rest Trigger listening on %s://%s", scheme, httpSrv.Addr)
+
+			var err error
+			if ep.TLS != nil {
+				err = httpSrv.ServeTLS(ln, "", "")
+			} else {
+				err = httpSrv.Serve(ln)
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		})
+	}
+
+	go func() {
+		if err := g.Wait(); err != nil {
+			s.running = false
+			log.RootLogger().Error(err)
+		}
+	}()
+
 	return nil
 }
 
@@ -133,33 +348,112 @@ func (s *Server) Stop() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	return s.srv.Shutdown(ctx)
+	var g errgroup.Group
+
+	if s.metricsSrv != nil {
+		g.Go(func() error { return s.metricsSrv.Shutdown(ctx) })
+	}
+
+	if s.acmeSrv != nil {
+		g.Go(func() error { return s.acmeSrv.Shutdown(ctx) })
+	}
+
+	for _, httpSrv := range s.servers {
+		httpSrv := httpSrv
+		g.Go(func() error { return httpSrv.Shutdown(ctx) })
+	}
+
+	if s.certReloader != nil {
+		g.Go(s.certReloader.close)
+	}
+
+	return g.Wait()
 }
 
 func (s *Server) validateStart() error {
 
-	//check if port is available
-	ln, err := net.Listen("tcp", s.srv.Addr)
-	if err != nil {
-		return err
+	if s.socketActivation {
+		// the listeners are inherited from systemd/init, not bound here
+		return nil
+	}
+
+	for _, ep := range s.endpoints {
+		network := ep.Network
+		if network == "" {
+			network = "tcp"
+		}
+
+		if network == "unix" {
+			if err := removeStaleUnixSocket(ep.Addr); err != nil {
+				return err
+			}
+		}
+
+		//check if the address is available
+		ln, err := net.Listen(network, ep.Addr)
+		if err != nil {
+			return err
+		}
+		ln.Close()
 	}
-	ln.Close()
 
 	return nil
 }
 
+// removeStaleUnixSocket unlinks addr if it's a leftover unix socket file
+// from a prior, non-graceful exit, so net.Listen can bind it again. A file
+// is only removed once dialing it confirms nothing is listening anymore;
+// an in-use socket is reported as an error instead.
+func removeStaleUnixSocket(addr string) error {
+	if _, err := os.Stat(addr); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	conn, err := net.DialTimeout("unix", addr, 100*time.Millisecond)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("address %s already in use", addr)
+	}
+
+	return os.Remove(addr)
+}
+
 func (s *Server) validateInit() error {
 
-	if s.tlsEnabled {
-		// using tls, so validate cert & key
+	if !s.tlsEnabled && !s.autoTLSEnabled {
+		return nil
+	}
+
+	getCertificate, err := s.certificateSource()
+	if err != nil {
+		return err
+	}
+
+	nextProtos := []string{"http/1.1"}
+	if s.http2Enabled {
+		nextProtos = []string{"h2", "http/1.1"}
+	}
 
-		if s.certFile == "" || s.keyFile == "" {
-			return fmt.Errorf("when TLS is enabled, both cert file and key file must be specified")
+	for i := range s.endpoints {
+		cfg := s.endpoints[i].TLS
+		if cfg == nil {
+			continue
 		}
 
-		_, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
-		if err != nil {
-			return err
+		if cfg.MinVersion == 0 {
+			cfg.MinVersion = tls.VersionTLS12
+		}
+		if len(cfg.CipherSuites) == 0 {
+			cfg.CipherSuites = defaultCipherSuites
+		}
+		if cfg.GetCertificate == nil && len(cfg.Certificates) == 0 {
+			cfg.GetCertificate = getCertificate
+		}
+		if cfg.NextProtos == nil {
+			cfg.NextProtos = nextProtos
 		}
 	}
 
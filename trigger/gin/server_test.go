@@ -0,0 +1,319 @@
+package gin
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// TestServerH2CNegotiatesHTTP2 is a smoke test for the H2C option: it
+// starts a server with H2C enabled and no TLS, and checks that a client
+// that only speaks HTTP/2 (via prior-knowledge, as h2c requires) gets
+// served over HTTP/2 rather than falling back to HTTP/1.1.
+func TestServerH2CNegotiatesHTTP2(t *testing.T) {
+	port, err := freeTCPPort(t)
+	if err != nil {
+		t.Fatalf("freeTCPPort: %v", err)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Proto)
+	})
+
+	srv, err := NewServer([]Endpoint{{Addr: fmt.Sprintf("127.0.0.1:%d", port)}}, handler, H2C())
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer srv.Stop()
+
+	waitForListener(t, port)
+
+	client := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(_ context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			},
+		},
+	}
+
+	resp, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d/", port))
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("expected an HTTP/2 response, got %s", resp.Proto)
+	}
+}
+
+// TestServerALPNNegotiatesHTTP2 is a smoke test for the HTTP2 option over
+// TLS: it starts a server with a self-signed cert and checks that a
+// client negotiates h2 via ALPN rather than falling back to HTTP/1.1.
+func TestServerALPNNegotiatesHTTP2(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	generateSelfSignedCert(t, certFile, keyFile)
+
+	port, err := freeTCPPort(t)
+	if err != nil {
+		t.Fatalf("freeTCPPort: %v", err)
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Proto)
+	})
+
+	endpoint := Endpoint{Addr: fmt.Sprintf("127.0.0.1:%d", port), TLS: &tls.Config{}}
+	srv, err := NewServer([]Endpoint{endpoint}, handler, TLS(certFile, keyFile), HTTP2(nil))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer srv.Stop()
+
+	waitForListener(t, port)
+
+	transport := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	if err := http2.ConfigureTransport(transport); err != nil {
+		t.Fatalf("ConfigureTransport: %v", err)
+	}
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get(fmt.Sprintf("https://127.0.0.1:%d/", port))
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.TLS == nil || resp.TLS.NegotiatedProtocol != "h2" {
+		t.Fatalf("expected ALPN to negotiate h2, got %+v", resp.TLS)
+	}
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("expected an HTTP/2 response, got %s", resp.Proto)
+	}
+}
+
+// TestServerH2CStreamsResponse checks that a response is flushed to the
+// client as the handler writes it, rather than being buffered until the
+// handler returns. It reads the first chunk before unblocking the
+// handler's second write, so a non-streaming implementation fails on the
+// 2s timeout.
+func TestServerH2CStreamsResponse(t *testing.T) {
+	port, err := freeTCPPort(t)
+	if err != nil {
+		t.Fatalf("freeTCPPort: %v", err)
+	}
+
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "no flusher", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, "first-chunk\n")
+		flusher.Flush()
+		<-release
+		fmt.Fprint(w, "second-chunk\n")
+	})
+
+	srv, err := NewServer([]Endpoint{{Addr: fmt.Sprintf("127.0.0.1:%d", port)}}, handler, H2C())
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer srv.Stop()
+
+	waitForListener(t, port)
+
+	client := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(_ context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			},
+		},
+	}
+
+	resp, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d/", port))
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+
+	type readResult struct {
+		line string
+		err  error
+	}
+	lines := make(chan readResult, 1)
+	go func() {
+		line, err := reader.ReadString('\n')
+		lines <- readResult{line, err}
+	}()
+
+	select {
+	case r := <-lines:
+		if r.err != nil {
+			t.Fatalf("ReadString: %v", r.err)
+		}
+		if r.line != "first-chunk\n" {
+			t.Fatalf("expected %q, got %q", "first-chunk\n", r.line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for the first chunk; response is not streaming")
+	}
+
+	close(release)
+
+	rest, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("ReadString: %v", err)
+	}
+	if rest != "second-chunk\n" {
+		t.Fatalf("expected %q, got %q", "second-chunk\n", rest)
+	}
+}
+
+// TestServerUnixEndpointSurvivesStaleSocket checks that a leftover, unused
+// unix socket file from a prior ungraceful exit doesn't block a later
+// Start on the same path.
+func TestServerUnixEndpointSurvivesStaleSocket(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "trigger.sock")
+
+	stale, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	// Simulate an ungraceful exit: the listener goes away but its socket
+	// file is left on disk, since Close would otherwise unlink it.
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	endpoint := Endpoint{Network: "unix", Addr: sockPath}
+	srv, err := NewServer([]Endpoint{endpoint}, http.NotFoundHandler())
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start did not clean up the stale socket file: %v", err)
+	}
+	defer srv.Stop()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		conn, err := net.Dial("unix", sockPath)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server never started listening on %s", sockPath)
+}
+
+// generateSelfSignedCert writes a freshly generated, self-signed
+// certificate/key pair for "localhost" to certPath/keyPath.
+func generateSelfSignedCert(t *testing.T, certPath, keyPath string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		t.Fatalf("serial: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+
+	certOut, err := os.Create(certPath)
+	if err != nil {
+		t.Fatalf("create cert file: %v", err)
+	}
+	defer certOut.Close()
+	if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+		t.Fatalf("encode cert: %v", err)
+	}
+
+	keyOut, err := os.Create(keyPath)
+	if err != nil {
+		t.Fatalf("create key file: %v", err)
+	}
+	defer keyOut.Close()
+	if err := pem.Encode(keyOut, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}); err != nil {
+		t.Fatalf("encode key: %v", err)
+	}
+}
+
+// freeTCPPort returns the port of an ephemeral TCP listener on localhost,
+// closing it immediately so the caller can rebind it.
+func freeTCPPort(t *testing.T) (int, error) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer ln.Close()
+
+	return ln.Addr().(*net.TCPAddr).Port, nil
+}
+
+// waitForListener polls until 127.0.0.1:port accepts TCP connections, or fails the
+// test after a short timeout.
+func waitForListener(t *testing.T, port int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("server never started listening on port %d", port)
+}
@@ -0,0 +1,105 @@
+package gin
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/project-flogo/core/support/log"
+)
+
+// listenFDsStart is the first file descriptor systemd/init hands off under
+// the LISTEN_FDS convention; descriptors 0-2 are stdin/stdout/stderr.
+const listenFDsStart = 3
+
+// listenPIDSelfReload is the LISTEN_PID value Reload uses in place of a
+// real pid: the reloaded process's pid can't be known until after
+// os.StartProcess returns, so it can never match the child's own
+// os.Getpid(). It marks the handoff as trusted without a pid check; the
+// listeners themselves are only reachable because Reload passed them in
+// os.ProcAttr.Files to this specific child.
+const listenPIDSelfReload = "-"
+
+// activationListener returns the listener passed by systemd/init at the
+// given index, following the LISTEN_FDS/LISTEN_PID convention. It returns
+// an error if LISTEN_PID doesn't match this process or no listener was
+// passed at that index.
+func activationListener(index int) (net.Listener, error) {
+
+	if pidEnv := os.Getenv("LISTEN_PID"); pidEnv != listenPIDSelfReload {
+		pid, err := strconv.Atoi(pidEnv)
+		if err != nil || pid != os.Getpid() {
+			return nil, fmt.Errorf("socket activation requested but LISTEN_PID does not match this process")
+		}
+	}
+
+	nfds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || index >= nfds {
+		return nil, fmt.Errorf("socket activation requested but no listener was passed at index %d", index)
+	}
+
+	name := "listener"
+	if names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":"); index < len(names) && names[index] != "" {
+		name = names[index]
+	}
+
+	f := os.NewFile(uintptr(listenFDsStart+index), name)
+	defer f.Close()
+
+	return net.FileListener(f)
+}
+
+// Reload re-executes the current binary, handing off all of the server's
+// listeners to the new process via os.ProcAttr.Files (fd 3 onward) so it can pick up serving on
+// the same sockets with zero downtime. The server must have been started
+// with the SocketActivation option.
+func (s *Server) Reload() error {
+
+	if !s.socketActivation || len(s.listeners) == 0 {
+		return fmt.Errorf("reload requires listeners obtained via SocketActivation")
+	}
+
+	type filer interface {
+		File() (*os.File, error)
+	}
+
+	files := make([]*os.File, 0, len(s.listeners))
+	for _, ln := range s.listeners {
+		lf, ok := ln.(filer)
+		if !ok {
+			return fmt.Errorf("listener of type %T does not support Reload", ln)
+		}
+
+		f, err := lf.File()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		files = append(files, f)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	env := append(os.Environ(),
+		fmt.Sprintf("LISTEN_FDS=%d", len(files)),
+		"LISTEN_PID="+listenPIDSelfReload,
+	)
+
+	proc, err := os.StartProcess(executable, os.Args, &os.ProcAttr{
+		Env:   env,
+		Files: append([]*os.File{os.Stdin, os.Stdout, os.Stderr}, files...),
+	})
+	if err != nil {
+		return err
+	}
+
+	log.RootLogger().Infof("Rest Trigger handed off %d listener(s) to reloaded process pid %d", len(files), proc.Pid)
+
+	return nil
+}
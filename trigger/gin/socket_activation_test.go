@@ -0,0 +1,70 @@
+package gin
+
+import (
+	"net"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+// TestActivationListenerRejectsPIDMismatch ensures a LISTEN_PID that
+// doesn't match this process (the real systemd/init convention) is
+// rejected, rather than silently accepted.
+func TestActivationListenerRejectsPIDMismatch(t *testing.T) {
+	t.Setenv("LISTEN_PID", strconv.Itoa(-1))
+	t.Setenv("LISTEN_FDS", "1")
+
+	if _, err := activationListener(0); err == nil {
+		t.Fatalf("expected an error for a mismatched LISTEN_PID")
+	}
+}
+
+// TestActivationListenerRejectsMissingIndex ensures an index beyond
+// LISTEN_FDS is rejected.
+func TestActivationListenerRejectsMissingIndex(t *testing.T) {
+	t.Setenv("LISTEN_PID", listenPIDSelfReload)
+	t.Setenv("LISTEN_FDS", "1")
+
+	if _, err := activationListener(1); err == nil {
+		t.Fatalf("expected an error for an index beyond LISTEN_FDS")
+	}
+}
+
+// TestActivationListenerSelfReloadBypassesPIDCheck simulates the handoff
+// Reload performs: a listener's fd duplicated onto listenFDsStart and
+// LISTEN_PID set to listenPIDSelfReload, which activationListener must
+// accept without requiring its own pid to match (it can't - see Reload).
+func TestActivationListenerSelfReloadBypassesPIDCheck(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "activation.sock")
+
+	ln, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer ln.Close()
+
+	f, err := ln.(*net.UnixListener).File()
+	if err != nil {
+		t.Fatalf("File: %v", err)
+	}
+	defer f.Close()
+
+	if err := syscall.Dup2(int(f.Fd()), listenFDsStart); err != nil {
+		t.Fatalf("dup2: %v", err)
+	}
+
+	t.Setenv("LISTEN_PID", listenPIDSelfReload)
+	t.Setenv("LISTEN_FDS", "1")
+	t.Setenv("LISTEN_FDNAMES", "")
+
+	activated, err := activationListener(0)
+	if err != nil {
+		t.Fatalf("activationListener: %v", err)
+	}
+	defer activated.Close()
+
+	if activated.Addr().String() != sockPath {
+		t.Fatalf("expected listener on %s, got %s", sockPath, activated.Addr().String())
+	}
+}
@@ -1,24 +1,59 @@
 package gin
 
 import (
-	"bytes"
-	"context"
+	"crypto/tls"
 	"encoding/json"
 	"io"
+	"mime"
 	"net/http"
-	"net/url"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/project-flogo/core/data/metadata"
 	"github.com/project-flogo/core/support/log"
 	"github.com/project-flogo/core/trigger"
+	"go.opentelemetry.io/otel"
 
 	contrib "github.com/project-flogo/contrib/trigger/rest"
 )
 
-var ginMetadata = trigger.NewMetadata(&contrib.Settings{}, &contrib.HandlerSettings{}, &contrib.Output{}, &contrib.Reply{})
+// Settings extends the REST trigger's settings with gin-specific options.
+type Settings struct {
+	Port      int    `md:"port,required"`
+	EnableTLS bool   `md:"enableTLS"`
+	CertFile  string `md:"certFile"`
+	KeyFile   string `md:"keyFile"`
+	// TLSReload watches CertFile/KeyFile for changes and swaps the serving
+	// certificate without restarting the trigger.
+	TLSReload bool `md:"tlsReload"`
+	// EnableAutoTLS provisions and renews TLS certificates automatically
+	// via ACME/Let's Encrypt instead of using CertFile/KeyFile. AutoTLSHosts
+	// restricts which server names certificates are requested for, and
+	// AutoTLSCacheDir is where they're cached on disk.
+	EnableAutoTLS   bool     `md:"enableAutoTLS"`
+	AutoTLSHosts    []string `md:"autoTLSHosts"`
+	AutoTLSCacheDir string   `md:"autoTLSCacheDir"`
+	// LogFormat selects the access log encoding: "json" or "text" (default).
+	LogFormat string `md:"logFormat"`
+	// EnableObservability turns on OpenTelemetry tracing spans and
+	// Prometheus request metrics.
+	EnableObservability bool `md:"enableObservability"`
+	// MetricsAddr is the address the Prometheus /metrics endpoint listens
+	// on when EnableObservability is set. Defaults to httpDefaultMetricsAddr.
+	MetricsAddr string `md:"metricsAddr"`
+	// Addrs lists additional addresses to serve on alongside Port, so a
+	// single trigger instance can bind an HTTP admin port, an HTTPS public
+	// port, and/or a unix socket all at once.
+	Addrs struct {
+		HTTP  []string `md:"http"`
+		HTTPS []string `md:"https"`
+		Unix  []string `md:"unix"`
+	} `md:"addrs"`
+}
+
+var ginMetadata = trigger.NewMetadata(&Settings{}, &contrib.HandlerSettings{}, &contrib.Output{}, &contrib.Reply{})
 
 func init() {
 	_ = trigger.Register(&Trigger{}, &Factory{})
@@ -26,9 +61,14 @@ func init() {
 
 type Trigger struct {
 	id       string
-	settings *contrib.Settings
+	settings *Settings
 	server   *Server
 	logger   log.Logger
+	// codecs seeds the server's codec registry in Initialize via the
+	// Codecs option; decoding and response negotiation read the resulting
+	// registry off server, not this field, so it reflects any later
+	// overrides too.
+	codecs CodecRegistry
 }
 
 func (t *Trigger) Initialize(ctx trigger.InitContext) error {
@@ -36,9 +76,16 @@ func (t *Trigger) Initialize(ctx trigger.InitContext) error {
 	addr := ":" + strconv.Itoa(t.settings.Port)
 
 	// config := cors.DefaultConfig()
-	router := gin.Default()
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(newLoggingMiddleware(t.logger, t.settings.LogFormat))
 	// router.Use(cors.New(config))
 
+	if t.settings.EnableObservability {
+		router.Use(newTracingMiddleware(t.id, otel.GetTracerProvider()))
+		router.Use(newMetricsMiddleware(newRequestMetrics(prometheus.DefaultRegisterer)))
+	}
+
 	for _, handler := range ctx.GetHandlers() {
 		s := &contrib.HandlerSettings{}
 		err := metadata.MapToStruct(handler.Settings(), s, true)
@@ -56,13 +103,47 @@ func (t *Trigger) Initialize(ctx trigger.InitContext) error {
 
 	t.logger.Debugf("Configured on port %d", t.settings.Port)
 
+	var endpoints []Endpoint
+
+	if t.settings.Port != 0 {
+		ep := Endpoint{Addr: addr}
+		if t.settings.EnableTLS || t.settings.EnableAutoTLS {
+			ep.TLS = &tls.Config{}
+		}
+		endpoints = append(endpoints, ep)
+	}
+
+	for _, a := range t.settings.Addrs.HTTP {
+		endpoints = append(endpoints, Endpoint{Addr: a})
+	}
+	for _, a := range t.settings.Addrs.HTTPS {
+		endpoints = append(endpoints, Endpoint{Addr: a, TLS: &tls.Config{}})
+	}
+	for _, a := range t.settings.Addrs.Unix {
+		endpoints = append(endpoints, Endpoint{Network: "unix", Addr: a})
+	}
+
 	var opts []Option
 
 	if t.settings.EnableTLS {
 		opts = append(opts, TLS(t.settings.CertFile, t.settings.KeyFile))
 	}
 
-	server, err := NewServer(addr, router, opts...)
+	if t.settings.EnableAutoTLS {
+		opts = append(opts, AutoTLS(t.settings.AutoTLSHosts, t.settings.AutoTLSCacheDir))
+	}
+
+	if t.settings.TLSReload {
+		opts = append(opts, TLSReload())
+	}
+
+	opts = append(opts, Codecs(t.codecs))
+
+	if t.settings.EnableObservability {
+		opts = append(opts, Observability(otel.GetTracerProvider(), prometheus.DefaultRegisterer, t.settings.MetricsAddr))
+	}
+
+	server, err := NewServer(endpoints, router, opts...)
 	if err != nil {
 		return err
 	}
@@ -89,21 +170,19 @@ func (f *Factory) Metadata() *trigger.Metadata {
 }
 
 func (f *Factory) New(config *trigger.Config) (trigger.Trigger, error) {
-	s := &contrib.Settings{}
+	s := &Settings{}
 	err := metadata.MapToStruct(config.Settings, s, true)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Trigger{id: config.Id, settings: s}, nil
+	return &Trigger{id: config.Id, settings: s, codecs: defaultCodecs()}, nil
 }
 
 func newGinHandler(rt *Trigger, method string, handler trigger.Handler) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		logger := rt.logger
 
-		logger.Debugf("Received request for id '%s'", rt.id)
-
 		out := &contrib.Output{}
 		out.Method = method
 
@@ -125,48 +204,23 @@ func newGinHandler(rt *Trigger, method string, handler trigger.Handler) gin.Hand
 		}
 
 		contentType := c.Request.Header.Get("Content-Type")
-		switch contentType {
-		case "application/x-www-form-urlencoded":
-			buf := new(bytes.Buffer)
-			_, err := buf.ReadFrom(c.Request.Body)
-			if err != nil {
-				logger.Debugf("Error reading body: %s", err.Error())
-				http.Error(c.Writer, err.Error(), http.StatusBadRequest)
-				return
-			}
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			mediaType = contentType
+		}
 
-			s := buf.String()
-			m, err := url.ParseQuery(s)
+		if codec, ok := rt.server.codecs[mediaType]; ok {
+			content, err := codec.Decode(c.Request)
 			if err != nil {
-				logger.Debugf("Error parsing query string: %s", err.Error())
+				logger.Debugf("Error decoding %s body: %s", mediaType, err.Error())
 				http.Error(c.Writer, err.Error(), http.StatusBadRequest)
 				return
 			}
-
-			content := make(map[string]interface{}, 0)
-			for key, val := range m {
-				if len(val) == 1 {
-					content[key] = val[0]
-				} else {
-					content[key] = val[0]
-				}
-			}
-
-			out.Content = content
-		case "application/json":
-			var content interface{}
-			err := json.NewDecoder(c.Request.Body).Decode(&content)
-			if err != nil {
-				switch {
-				case err == io.EOF:
-				default:
-					logger.Debugf("Error parsing json body: %s", err.Error())
-					http.Error(c.Writer, err.Error(), http.StatusBadRequest)
-					return
-				}
+			if mediaType == mimeMultipart {
+				defer closeMultipartContent(c.Request, content)
 			}
 			out.Content = content
-		default:
+		} else {
 			b, err := io.ReadAll(c.Request.Body)
 			if err != nil {
 				logger.Debugf("Error reading body: %s", err.Error())
@@ -174,10 +228,9 @@ func newGinHandler(rt *Trigger, method string, handler trigger.Handler) gin.Hand
 				return
 			}
 			out.Content = string(b)
-			return
 		}
 
-		results, err := handler.Handle(context.Background(), out)
+		results, err := handler.Handle(c.Request.Context(), out)
 		if err != nil {
 			logger.Debugf("Error handling request: %s", err.Error())
 			http.Error(c.Writer, err.Error(), http.StatusBadRequest)
@@ -237,10 +290,11 @@ func newGinHandler(rt *Trigger, method string, handler trigger.Handler) gin.Hand
 				}
 				return
 			default:
-				c.Writer.Header().Set("Content-Type", "application/json; charset=UTF-8")
+				codec, mediaType := rt.negotiateCodec(c.GetHeader("Accept"))
+
 				c.Writer.WriteHeader(reply.Code)
-				if err := json.NewEncoder(c.Writer).Encode(reply.Data); err != nil {
-					logger.Debugf("Error encoding json reply: %s", err.Error())
+				if err := codec.Encode(c.Writer, reply.Data); err != nil {
+					logger.Debugf("Error encoding %s reply: %s", mediaType, err.Error())
 				}
 				return
 			}